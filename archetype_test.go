@@ -0,0 +1,107 @@
+package ecs
+
+import "testing"
+
+func TestArchetypeRemoveEntitySwapRemove(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	e1 := m.NewEntity().AddComponent(walk, "w1")
+	e2 := m.NewEntity().AddComponent(walk, "w2")
+	e3 := m.NewEntity().AddComponent(walk, "w3")
+
+	arch := m.archetypeOf[e2.ID]
+	values := arch.removeEntity(e2)
+
+	if got := values[walk.id]; got != "w2" {
+		t.Fatalf("expected removeEntity to hand back e2's data, got %v", got)
+	}
+
+	if _, ok := arch.rowOfID[e2.ID]; ok {
+		t.Fatalf("expected e2 to be gone from rowOfID")
+	}
+
+	// e3 (the last entity) should have been swapped into e2's old row.
+	row, ok := arch.rowOfID[e3.ID]
+	if !ok {
+		t.Fatalf("expected e3 to still be tracked after the swap")
+	}
+	if arch.entities[row] != e3 {
+		t.Fatalf("expected entities[%d] to be e3 after swap-remove", row)
+	}
+	if arch.data[arch.colOfID[walk.id]][row] != "w3" {
+		t.Fatalf("expected e3's data to have moved with it, got %v", arch.data[arch.colOfID[walk.id]][row])
+	}
+
+	if row1, ok := arch.rowOfID[e1.ID]; !ok || arch.entities[row1] != e1 {
+		t.Fatalf("expected e1 to be untouched by removing e2")
+	}
+
+	if len(arch.entities) != 2 {
+		t.Fatalf("expected 2 entities left, got %d", len(arch.entities))
+	}
+}
+
+func TestArchetypeRemoveEntityLastRow(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	e1 := m.NewEntity().AddComponent(walk, "w1")
+	e2 := m.NewEntity().AddComponent(walk, "w2")
+
+	arch := m.archetypeOf[e2.ID]
+	values := arch.removeEntity(e2)
+
+	if got := values[walk.id]; got != "w2" {
+		t.Fatalf("expected removeEntity to hand back e2's data, got %v", got)
+	}
+	if len(arch.entities) != 1 || arch.entities[0] != e1 {
+		t.Fatalf("expected only e1 to remain")
+	}
+}
+
+func TestArchetypeRemoveEntityUnknown(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	e1 := m.NewEntity().AddComponent(walk, "w1")
+	other := &Entity{ID: EntityID(9999)}
+
+	arch := m.archetypeOf[e1.ID]
+	if values := arch.removeEntity(other); values != nil {
+		t.Fatalf("expected nil for an entity the archetype doesn't hold, got %v", values)
+	}
+}
+
+func TestMigrateCarriesOverExistingData(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	e := m.NewEntity().AddComponent(walk, "w1")
+	e.AddComponent(talk, "t1")
+
+	data, ok := e.GetComponentData(walk)
+	if !ok || data != "w1" {
+		t.Fatalf("expected walk data to survive the migration to the walk+talk archetype, got %v, %v", data, ok)
+	}
+
+	talkData, ok := e.GetComponentData(talk)
+	if !ok || talkData != "t1" {
+		t.Fatalf("expected talk data to be set on the new archetype, got %v, %v", talkData, ok)
+	}
+}
+
+func TestReAddComponentAfterRemoveSetsFreshData(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	e := m.NewEntity().AddComponent(walk, "w1")
+	e.RemoveComponent(walk)
+	e.AddComponent(walk, "w2")
+
+	data, ok := e.GetComponentData(walk)
+	if !ok || data != "w2" {
+		t.Fatalf("expected re-adding walk to set fresh data, got %v, %v", data, ok)
+	}
+}
@@ -0,0 +1,90 @@
+package ecs
+
+import "sync/atomic"
+
+// structuralChangeKind identifies which operation a buffered
+// structuralChange replays once committed.
+type structuralChangeKind int
+
+const (
+	changeAddComponent structuralChangeKind = iota
+	changeRemoveComponent
+	changeDisposeEntity
+)
+
+// structuralChange is one AddComponent/RemoveComponent/DisposeEntity call
+// that was deferred because it happened while a Query or QueryEach was
+// iterating, recorded for CommitPending to replay later.
+type structuralChange struct {
+	kind      structuralChangeKind
+	entity    *Entity
+	component *Component
+	data      interface{}
+}
+
+// deferStructuralChanges reports whether a Query or QueryEach is currently
+// iterating on manager, on any goroutine. While true, AddComponent,
+// RemoveComponent and DisposeEntity buffer their change instead of taking
+// manager.lock directly, since doing so from inside a query's callback
+// would try to acquire the writer lock while that same goroutine already
+// holds the reader lock, deadlocking. The check is manager-wide rather than
+// scoped to the calling goroutine: a structural change made by one system
+// while any other system is mid-query on the same Manager is buffered too,
+// trading a little extra batching for a check that needs no goroutine-local
+// state.
+func (manager *Manager) deferStructuralChanges() bool {
+	return atomic.LoadInt32(&manager.queryDepth) > 0
+}
+
+// deferOrEnqueue buffers change for the next CommitPending and reports true
+// if a Query/QueryEach is in flight on manager, in which case the caller
+// must not apply change itself. Otherwise it reports false and leaves
+// change untouched for the caller to apply immediately under manager.lock.
+func (manager *Manager) deferOrEnqueue(change structuralChange) bool {
+	if !manager.deferStructuralChanges() {
+		return false
+	}
+
+	manager.pendingLock.Lock()
+	manager.pending = append(manager.pending, change)
+	manager.pendingLock.Unlock()
+
+	return true
+}
+
+// CommitPending applies every AddComponent, RemoveComponent and
+// DisposeEntity call that was deferred because it happened during a Query
+// or QueryEach, in the order they were made, atomically under a single
+// manager.lock acquisition. It's a no-op if nothing is pending. Scheduler
+// calls it automatically at the end of each Tick; call it directly when
+// running Query/QueryEach outside a Scheduler, but never from inside a
+// Query/QueryEach callback — manager.lock.Lock() would block forever behind
+// that same goroutine's own read lock. Any OnAdd/OnRemove/Subscribe hooks
+// the batch triggers run after manager.lock has been released, same as
+// when AddComponent/RemoveComponent/DisposeEntity apply immediately.
+func (manager *Manager) CommitPending() {
+	manager.pendingLock.Lock()
+	pending := manager.pending
+	manager.pending = nil
+	manager.pendingLock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	manager.lock.Lock()
+	var hooks []func()
+	for _, change := range pending {
+		switch change.kind {
+		case changeAddComponent:
+			hooks = append(hooks, manager.addComponent(change.entity, change.component, change.data)...)
+		case changeRemoveComponent:
+			hooks = append(hooks, manager.removeComponent(change.entity, change.component)...)
+		case changeDisposeEntity:
+			hooks = append(hooks, manager.disposeEntity(change.entity)...)
+		}
+	}
+	manager.lock.Unlock()
+
+	runHooks(hooks)
+}
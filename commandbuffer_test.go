@@ -0,0 +1,73 @@
+package ecs
+
+import "testing"
+
+type systemFunc func(dt float64, world *Manager)
+
+func (f systemFunc) Run(dt float64, world *Manager) { f(dt, world) }
+
+func TestQueryEachDeferredAddComponent(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	m.NewEntity().AddComponent(walk, "w1")
+
+	m.QueryEach(BuildTag(walk), func(e *Entity, _ ...interface{}) {
+		e.AddComponent(talk, "t1")
+	})
+
+	if got := len(m.Query(BuildTag(walk, talk))); got != 0 {
+		t.Fatalf("expected the add to stay pending before CommitPending, got %d matches", got)
+	}
+
+	m.CommitPending()
+
+	if got := len(m.Query(BuildTag(walk, talk))); got != 1 {
+		t.Fatalf("expected CommitPending to apply the deferred add, got %d matches", got)
+	}
+}
+
+func TestQueryEachDeferredDisposeEntity(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	e := m.NewEntity().AddComponent(walk, "w1")
+
+	m.QueryEach(BuildTag(walk), func(entity *Entity, _ ...interface{}) {
+		m.DisposeEntity(entity)
+	})
+
+	if m.GetEntityByID(e.ID) == nil {
+		t.Fatalf("expected dispose to stay pending before CommitPending")
+	}
+
+	m.CommitPending()
+
+	if m.GetEntityByID(e.ID) != nil {
+		t.Fatalf("expected CommitPending to apply the deferred dispose")
+	}
+}
+
+func TestSchedulerTickAutoCommitsPending(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	m.NewEntity().AddComponent(walk, "w1")
+
+	scheduler := NewScheduler(m)
+	scheduler.Register(0, systemFunc(func(dt float64, world *Manager) {
+		world.QueryEach(BuildTag(walk), func(e *Entity, _ ...interface{}) {
+			e.AddComponent(talk, "t1")
+		})
+	}), walk.tag, talk.tag)
+
+	if err := scheduler.Tick(0.1); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	if got := len(m.Query(BuildTag(walk, talk))); got != 1 {
+		t.Fatalf("expected Tick to auto-commit the deferred add, got %d matches", got)
+	}
+}
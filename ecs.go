@@ -1,7 +1,9 @@
 package ecs
 
 import (
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -14,13 +16,82 @@ func (id EntityID) String() string {
 
 type ComponentID uint32
 
+// Tag is a bitset identifying a set of components. The first 64 bits live
+// inline in word0, so tags built from up to 64 components never allocate;
+// beyond that, bits spill into extra, one uint64 per additional 64
+// components.
 type Tag struct {
-	flags   uint64 // limited to 64 components
+	word0   uint64
+	extra   []uint64
 	inverse bool
+	anyOf   []Tag // if set, matches() also requires a match against at least one of these
+}
+
+// numWords returns how many uint64 words this tag's bitset spans.
+func (tag Tag) numWords() int {
+	return 1 + len(tag.extra)
+}
+
+// wordAt returns word i of the bitset (0 if i is beyond the tag's own words).
+func (tag Tag) wordAt(i int) uint64 {
+	if i == 0 {
+		return tag.word0
+	}
+
+	idx := i - 1
+	if idx < len(tag.extra) {
+		return tag.extra[idx]
+	}
+
+	return 0
+}
+
+// setWord sets word i of the bitset, growing extra as needed.
+func (tag *Tag) setWord(i int, word uint64) {
+	if i == 0 {
+		tag.word0 = word
+		return
+	}
+
+	idx := i - 1
+	for len(tag.extra) <= idx {
+		tag.extra = append(tag.extra, 0)
+	}
+	tag.extra[idx] = word
+}
+
+// setBit sets the bit at the given position, growing the bitset as needed.
+func (tag *Tag) setBit(pos uint32) {
+	wordIdx := int(pos / 64)
+	bitIdx := pos % 64
+	tag.setWord(wordIdx, tag.wordAt(wordIdx)|(uint64(1)<<bitIdx))
 }
 
 func (tag Tag) matches(smallertag Tag) bool {
-	res := tag.flags&smallertag.flags == smallertag.flags
+
+	res := true
+	n := smallertag.numWords()
+	if tn := tag.numWords(); tn > n {
+		n = tn
+	}
+
+	for i := 0; i < n; i++ {
+		if tag.wordAt(i)&smallertag.wordAt(i) != smallertag.wordAt(i) {
+			res = false
+			break
+		}
+	}
+
+	if res && len(smallertag.anyOf) > 0 {
+		any := false
+		for _, alt := range smallertag.anyOf {
+			if tag.matches(alt) {
+				any = true
+				break
+			}
+		}
+		res = any
+	}
 
 	if smallertag.inverse {
 		return !res
@@ -30,17 +101,30 @@ func (tag Tag) matches(smallertag Tag) bool {
 }
 
 func (tag *Tag) binaryORInPlace(othertag Tag) *Tag {
-	tag.flags |= othertag.flags
+	n := othertag.numWords()
+	for i := 0; i < n; i++ {
+		tag.setWord(i, tag.wordAt(i)|othertag.wordAt(i))
+	}
 	return tag
 }
 
 func (tag *Tag) binaryNOTInPlace(othertag Tag) *Tag {
-	tag.flags ^= othertag.flags
+	n := othertag.numWords()
+	for i := 0; i < n; i++ {
+		tag.setWord(i, tag.wordAt(i)^othertag.wordAt(i))
+	}
 	return tag
 }
 
 func (tag Tag) clone() Tag {
-	return tag
+	clone := tag
+	if tag.extra != nil {
+		clone.extra = append([]uint64(nil), tag.extra...)
+	}
+	if tag.anyOf != nil {
+		clone.anyOf = append([]Tag(nil), tag.anyOf...)
+	}
+	return clone
 }
 
 func (tag Tag) Inverse(values ...bool) Tag {
@@ -55,6 +139,77 @@ func (tag Tag) Inverse(values ...bool) Tag {
 	return clone
 }
 
+// combineTagBits returns a fresh Tag whose bitset is op applied word-by-word
+// to a and b; inverse and anyOf are not carried over.
+func combineTagBits(a, b Tag, op func(x, y uint64) uint64) Tag {
+	n := a.numWords()
+	if bn := b.numWords(); bn > n {
+		n = bn
+	}
+
+	result := Tag{}
+	for i := 0; i < n; i++ {
+		result.setWord(i, op(a.wordAt(i), b.wordAt(i)))
+	}
+
+	return result
+}
+
+// And returns the bitset intersection of tag and other: the components
+// required by both.
+func (tag Tag) And(other Tag) Tag {
+	return combineTagBits(tag, other, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or returns the bitset union of tag and other: the components required by
+// either, i.e. requiring both at once (this is how BuildTag composes an
+// AND-of-components tag).
+func (tag Tag) Or(other Tag) Tag {
+	clone := tag.clone()
+	clone.binaryORInPlace(other)
+	return clone
+}
+
+// AndNot returns tag with other's bits cleared: the components required by
+// tag but not by other.
+func (tag Tag) AndNot(other Tag) Tag {
+	return combineTagBits(tag, other, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// All folds tags into tag via Or, so the result requires every component
+// from tag and from each of tags (an AND-of-groups predicate).
+func (tag Tag) All(tags ...Tag) Tag {
+	clone := tag.clone()
+	for _, other := range tags {
+		clone.binaryORInPlace(other)
+	}
+	return clone
+}
+
+// Any returns tag with tags appended as match alternatives: an entity
+// matches if it satisfies tag's own bits AND matches at least one of tags
+// (an OR-of-groups predicate, which a single AND-of-bits Tag can't express).
+func (tag Tag) Any(tags ...Tag) Tag {
+	clone := tag.clone()
+	clone.anyOf = append(clone.anyOf, tags...)
+	return clone
+}
+
+// isEmpty reports whether tag's bitset has no bit set.
+func (tag Tag) isEmpty() bool {
+	if tag.word0 != 0 {
+		return false
+	}
+
+	for _, word := range tag.extra {
+		if word != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 type View struct {
 	tag      Tag
 	entities QueryResultCollection
@@ -78,12 +233,12 @@ func (v View) Get() QueryResultCollection {
 	return v.entities
 }
 
-func (v *View) add(entity *Entity) {
+// add appends entity to the view. result is the QueryResult the caller
+// already fetched for entity against v.tag (CreateView's EventEnter handler
+// does so via GetEntityByID); add stores it as given.
+func (v *View) add(entity *Entity, result *QueryResult) {
 	v.lock.Lock()
-	v.entities = append(v.entities, entity.manager.GetEntityByID(
-		entity.ID,
-		v.tag,
-	))
+	v.entities = append(v.entities, result)
 	v.lock.Unlock()
 }
 
@@ -100,33 +255,145 @@ func (v *View) remove(entity *Entity) {
 	v.lock.RUnlock()
 }
 
+// archetype groups every entity sharing the exact same component set behind
+// contiguous, column-oriented slices (one slice per component) so that
+// Query/QueryEach can walk matching entities without per-entity map lookups.
+type archetype struct {
+	tag        Tag
+	components []*Component // sorted by ComponentID, defines the column order
+	colOfID    map[ComponentID]int
+	entities   []*Entity
+	data       [][]interface{} // data[column][row]
+	rowOfID    map[EntityID]int
+}
+
+func newArchetype(components []*Component) *archetype {
+	tag := Tag{}
+	colOfID := make(map[ComponentID]int, len(components))
+	for i, component := range components {
+		tag.binaryORInPlace(component.tag)
+		colOfID[component.id] = i
+	}
+
+	return &archetype{
+		tag:        tag,
+		components: components,
+		colOfID:    colOfID,
+		rowOfID:    make(map[EntityID]int),
+		data:       make([][]interface{}, len(components)),
+	}
+}
+
+// requiredColumns returns the indices of the columns that a query for tag
+// needs to read, in the archetype's column order.
+func (arch *archetype) requiredColumns(tag Tag) []int {
+	cols := make([]int, 0, len(arch.components))
+	for i, component := range arch.components {
+		if tag.matches(component.tag) {
+			cols = append(cols, i)
+		}
+	}
+
+	return cols
+}
+
+func (arch *archetype) addEntity(entity *Entity, values map[ComponentID]interface{}) {
+	row := len(arch.entities)
+	arch.entities = append(arch.entities, entity)
+	for i, component := range arch.components {
+		arch.data[i] = append(arch.data[i], values[component.id])
+	}
+	arch.rowOfID[entity.ID] = row
+}
+
+// removeEntity evicts entity from the archetype (swap-remove, so iteration
+// order isn't preserved) and hands back the data it held, keyed by
+// ComponentID, so the caller can carry it over to another archetype.
+func (arch *archetype) removeEntity(entity *Entity) map[ComponentID]interface{} {
+	row, ok := arch.rowOfID[entity.ID]
+	if !ok {
+		return nil
+	}
+
+	values := make(map[ComponentID]interface{}, len(arch.components))
+	for i, component := range arch.components {
+		values[component.id] = arch.data[i][row]
+	}
+
+	last := len(arch.entities) - 1
+	moved := arch.entities[last]
+	arch.entities[row] = moved
+	arch.entities = arch.entities[:last]
+	for i := range arch.data {
+		arch.data[i][row] = arch.data[i][last]
+		arch.data[i] = arch.data[i][:last]
+	}
+
+	if moved.ID != entity.ID {
+		arch.rowOfID[moved.ID] = row
+	}
+	delete(arch.rowOfID, entity.ID)
+
+	return values
+}
+
 type Manager struct {
 	lock            *sync.RWMutex
 	entityIdInc     uint32
-	componentNumInc uint32 // limited to 64
+	componentNumInc uint32
 
 	entities     []*Entity
 	entitiesByID map[EntityID]*Entity
 	components   []*Component
-	views        []*View
+
+	subscriptions []*subscription
+
+	archetypes  map[string]*archetype
+	archetypeOf map[EntityID]*archetype
+
+	codec              Codec
+	componentFactories map[ComponentID]func() interface{}
+
+	queryDepth  int32
+	pendingLock sync.Mutex
+	pending     []structuralChange
 }
 
 type Component struct {
 	id         ComponentID
 	tag        Tag
-	datalock   *sync.RWMutex
-	data       map[EntityID]interface{}
 	destructor func(entity *Entity, data interface{})
+	onAdd      []func(entity *Entity, data interface{})
+	onRemove   []func(entity *Entity, data interface{})
 }
 
 func (component *Component) SetDestructor(destructor func(entity *Entity, data interface{})) {
 	component.destructor = destructor
 }
 
+// OnAdd registers handler to be called every time AddComponent attaches
+// this component to an entity, right after its data is stored. Multiple
+// handlers may be registered; they run in registration order.
+func (component *Component) OnAdd(handler func(entity *Entity, data interface{})) {
+	component.onAdd = append(component.onAdd, handler)
+}
+
+// OnRemove registers handler to be called every time this component is
+// detached from an entity, via RemoveComponent, DisposeEntity or Restore.
+// It runs once the component has been removed and manager.lock released,
+// and receives the data the component held. Multiple handlers may be
+// registered; they run in registration order.
+func (component *Component) OnRemove(handler func(entity *Entity, data interface{})) {
+	component.onRemove = append(component.onRemove, handler)
+}
+
 func (component Component) GetID() ComponentID {
 	return component.id
 }
 
+// CreateView returns a View that stays in sync with every entity matching
+// the given tag elements, kept up to date via a Subscribe registration
+// rather than by polling Query.
 func (manager *Manager) CreateView(tagelements ...interface{}) *View {
 
 	tag := BuildTag(tagelements...)
@@ -136,13 +403,27 @@ func (manager *Manager) CreateView(tagelements ...interface{}) *View {
 		lock: &sync.RWMutex{},
 	}
 
-	entities := manager.Query(tag)
-	view.entities = make(QueryResultCollection, len(entities))
 	manager.lock.Lock()
-	for i, entityresult := range entities {
-		view.entities[i] = entityresult
-	}
-	manager.views = append(manager.views, view)
+	view.entities = manager.fetchView(tag)
+	manager.subscriptions = append(manager.subscriptions,
+		&subscription{
+			tag:  tag,
+			kind: EventEnter,
+			handler: func(entity *Entity) {
+				// Subscribe handlers run after the triggering call has
+				// released manager.lock, so GetEntityByID (which takes its
+				// own RLock) is safe to call here.
+				if result := manager.GetEntityByID(entity.ID, tag); result != nil {
+					view.add(entity, result)
+				}
+			},
+		},
+		&subscription{
+			tag:     tag,
+			kind:    EventExit,
+			handler: view.remove,
+		},
+	)
 	manager.lock.Unlock()
 
 	return view
@@ -160,11 +441,14 @@ func (entity *Entity) GetID() EntityID {
 
 func NewManager() *Manager {
 	return &Manager{
-		entityIdInc:     0,
-		componentNumInc: 0,
-		entitiesByID:    make(map[EntityID]*Entity),
-		lock:            &sync.RWMutex{},
-		views:           make([]*View, 0),
+		entityIdInc:        0,
+		componentNumInc:    0,
+		entitiesByID:       make(map[EntityID]*Entity),
+		lock:               &sync.RWMutex{},
+		archetypes:         make(map[string]*archetype),
+		archetypeOf:        make(map[EntityID]*archetype),
+		codec:              JSONCodec{},
+		componentFactories: make(map[ComponentID]func() interface{}),
 	}
 }
 
@@ -205,6 +489,8 @@ func (manager *Manager) NewEntity() *Entity {
 	manager.lock.Lock()
 	manager.entities = append(manager.entities, entity)
 	manager.entitiesByID[entity.ID] = entity
+	manager.archetypeOf[entity.ID] = manager.getArchetype(nil)
+	manager.archetypeOf[entity.ID].addEntity(entity, nil)
 	manager.lock.Unlock()
 
 	return entity
@@ -212,23 +498,15 @@ func (manager *Manager) NewEntity() *Entity {
 
 func (manager *Manager) NewComponent() *Component {
 
-	if manager.componentNumInc >= 63 {
-		panic("Component overflow (limited to 64)")
-	}
-
 	nextid := ComponentID(atomic.AddUint32(&manager.componentNumInc, 1))
 	id := nextid - 1 // to start at 0
 
-	tag := Tag{
-		flags:   (1 << id), // set bit on position corresponding to component number
-		inverse: false,
-	}
+	tag := Tag{}
+	tag.setBit(uint32(id)) // set bit on position corresponding to component number
 
 	component := &Component{
-		id:       id,
-		tag:      tag,
-		data:     make(map[EntityID]interface{}),
-		datalock: &sync.RWMutex{},
+		id:  id,
+		tag: tag,
 	}
 
 	manager.lock.Lock()
@@ -238,7 +516,7 @@ func (manager *Manager) NewComponent() *Component {
 	return component
 }
 
-func (manager Manager) GetEntityByID(id EntityID, tagelements ...interface{}) *QueryResult {
+func (manager *Manager) GetEntityByID(id EntityID, tagelements ...interface{}) *QueryResult {
 
 	manager.lock.RLock()
 	res, ok := manager.entitiesByID[id]
@@ -268,47 +546,204 @@ func (entity Entity) Matches(tag Tag) bool {
 	return entity.tag.matches(tag)
 }
 
-func (entity *Entity) AddComponent(component *Component, componentdata interface{}) *Entity {
-	component.datalock.Lock()
-	component.data[entity.ID] = componentdata
-	component.datalock.Unlock()
+// archetypeKey returns a canonical key for a set of components, sorted by
+// ComponentID. Two component sets produce the same key iff they hold the
+// same components, regardless of the order they were added in.
+func archetypeKey(sortedComponents []*Component) string {
+	var b strings.Builder
+	for _, component := range sortedComponents {
+		b.WriteString(strconv.FormatUint(uint64(component.id), 10))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
 
-	component.datalock.RLock()
+// getArchetype returns the archetype holding exactly the given set of
+// components (order-independent), creating it on first use. Callers must
+// hold manager.lock.
+func (manager *Manager) getArchetype(components []*Component) *archetype {
+	sorted := make([]*Component, len(components))
+	copy(sorted, components)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].id < sorted[j].id
+	})
 
-	tagbefore := entity.tag
-	entity.tag.binaryORInPlace(component.tag)
+	key := archetypeKey(sorted)
+
+	if arch, ok := manager.archetypes[key]; ok {
+		return arch
+	}
+
+	arch := newArchetype(sorted)
+	manager.archetypes[key] = arch
+	return arch
+}
 
-	for _, view := range entity.manager.views {
+// migrate moves entity into the archetype matching newComponents, carrying
+// over any data it already held and applying overrides on top. Callers must
+// hold manager.lock.
+func (manager *Manager) migrate(entity *Entity, newComponents []*Component, overrides map[ComponentID]interface{}) {
+	oldArch := manager.archetypeOf[entity.ID]
 
-		if !tagbefore.matches(view.tag) && entity.tag.matches(view.tag) {
-			view.add(entity)
+	values := make(map[ComponentID]interface{})
+	if oldArch != nil {
+		for id, data := range oldArch.removeEntity(entity) {
+			values[id] = data
 		}
 	}
+	for id, data := range overrides {
+		values[id] = data
+	}
+
+	newArch := manager.getArchetype(newComponents)
+	newArch.addEntity(entity, values)
+	manager.archetypeOf[entity.ID] = newArch
+
+	tag := Tag{}
+	for _, component := range newComponents {
+		tag.binaryORInPlace(component.tag)
+	}
+	entity.tag = tag
+}
+
+// componentHooks returns a hook call for every OnAdd (add is true) or
+// OnRemove (add is false) handler registered on component, for the caller
+// to run once it has released manager.lock.
+func componentHooks(component *Component, add bool, entity *Entity, data interface{}) []func() {
+	handlers := component.onRemove
+	if add {
+		handlers = component.onAdd
+	}
+
+	hooks := make([]func(), 0, len(handlers))
+	for _, handler := range handlers {
+		handler := handler
+		hooks = append(hooks, func() { handler(entity, data) })
+	}
+
+	return hooks
+}
+
+// AddComponent attaches component to entity. If called while a Query or
+// QueryEach is iterating on this Manager (on any goroutine), the change is
+// deferred to the Manager's pending buffer instead of applied immediately,
+// so it can't deadlock against the read lock a query holds; see
+// CommitPending. Any OnAdd/Subscribe hooks the change triggers run after
+// manager.lock has been released.
+func (entity *Entity) AddComponent(component *Component, componentdata interface{}) *Entity {
+	manager := entity.manager
+
+	if manager.deferOrEnqueue(structuralChange{
+		kind:      changeAddComponent,
+		entity:    entity,
+		component: component,
+		data:      componentdata,
+	}) {
+		return entity
+	}
+
+	manager.lock.Lock()
+	hooks := manager.addComponent(entity, component, componentdata)
+	manager.lock.Unlock()
+
+	runHooks(hooks)
 
-	component.datalock.RUnlock()
 	return entity
 }
 
+// addComponent is the lock-free core of AddComponent, reused by Restore
+// and CommitPending while they rebuild the world under a single
+// manager.lock. It returns the OnAdd/Subscribe hooks the change triggered,
+// for the caller to run once it has released manager.lock. Callers must
+// hold manager.lock.
+func (manager *Manager) addComponent(entity *Entity, component *Component, componentdata interface{}) []func() {
+	tagbefore := entity.tag
+	oldArch := manager.archetypeOf[entity.ID]
+
+	if oldArch != nil {
+		if col, ok := oldArch.colOfID[component.id]; ok {
+			// Entity already carries this component: just replace its data,
+			// no archetype migration needed.
+			oldArch.data[col][oldArch.rowOfID[entity.ID]] = componentdata
+			return componentHooks(component, true, entity, componentdata)
+		}
+	}
+
+	newComponents := make([]*Component, 0)
+	if oldArch != nil {
+		newComponents = append(newComponents, oldArch.components...)
+	}
+	newComponents = append(newComponents, component)
+
+	manager.migrate(entity, newComponents, map[ComponentID]interface{}{
+		component.id: componentdata,
+	})
+
+	hooks := componentHooks(component, true, entity, componentdata)
+	hooks = append(hooks, manager.tagChangeHooks(entity, tagbefore)...)
+	return hooks
+}
+
+// RemoveComponent detaches component from entity. Deferred to the pending
+// buffer instead of applied immediately when called during a Query or
+// QueryEach; see AddComponent and CommitPending. Any OnRemove/Subscribe
+// hooks the change triggers run after manager.lock has been released.
 func (entity *Entity) RemoveComponent(component *Component) *Entity {
+	manager := entity.manager
+
+	if manager.deferOrEnqueue(structuralChange{
+		kind:      changeRemoveComponent,
+		entity:    entity,
+		component: component,
+	}) {
+		return entity
+	}
+
+	manager.lock.Lock()
+	hooks := manager.removeComponent(entity, component)
+	manager.lock.Unlock()
+
+	runHooks(hooks)
+
+	return entity
+}
+
+// removeComponent is the lock-free core of RemoveComponent, reused by
+// CommitPending. It returns the OnRemove/Subscribe hooks the change
+// triggered, for the caller to run once it has released manager.lock.
+// Callers must hold manager.lock. component's destructor, unlike OnRemove,
+// still runs synchronously here, matching its pre-existing contract as a
+// teardown step rather than an observer.
+func (manager *Manager) removeComponent(entity *Entity, component *Component) []func() {
+	oldArch := manager.archetypeOf[entity.ID]
+	col, hasComponent := -1, false
+	if oldArch != nil {
+		col, hasComponent = oldArch.colOfID[component.id]
+	}
+
+	if !hasComponent {
+		return nil
+	}
+
+	data := oldArch.data[col][oldArch.rowOfID[entity.ID]]
 	if component.destructor != nil {
-		if data, ok := component.data[entity.ID]; ok {
-			component.destructor(entity, data)
-		}
+		component.destructor(entity, data)
 	}
+	hooks := componentHooks(component, false, entity, data)
 
-	component.datalock.Lock()
-	delete(component.data, entity.ID)
 	tagbefore := entity.tag
-	entity.tag.binaryNOTInPlace(component.tag)
 
-	for _, view := range entity.manager.views {
-		if tagbefore.matches(view.tag) && !entity.tag.matches(view.tag) {
-			view.remove(entity)
+	newComponents := make([]*Component, 0, len(oldArch.components)-1)
+	for _, c := range oldArch.components {
+		if c.id != component.id {
+			newComponents = append(newComponents, c)
 		}
 	}
 
-	component.datalock.Unlock()
-	return entity
+	manager.migrate(entity, newComponents, nil)
+
+	hooks = append(hooks, manager.tagChangeHooks(entity, tagbefore)...)
+	return hooks
 }
 
 func (entity Entity) HasComponent(component *Component) bool {
@@ -316,11 +751,22 @@ func (entity Entity) HasComponent(component *Component) bool {
 }
 
 func (entity Entity) GetComponentData(component *Component) (interface{}, bool) {
-	component.datalock.RLock()
-	data, ok := component.data[entity.ID]
-	component.datalock.RUnlock()
+	manager := entity.manager
 
-	return data, ok
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	arch := manager.archetypeOf[entity.ID]
+	if arch == nil {
+		return nil, false
+	}
+
+	col, ok := arch.colOfID[component.id]
+	if !ok {
+		return nil, false
+	}
+
+	return arch.data[col][arch.rowOfID[entity.ID]], true
 }
 
 func (manager *Manager) DisposeEntities(entities ...*Entity) {
@@ -329,6 +775,12 @@ func (manager *Manager) DisposeEntities(entities ...*Entity) {
 	}
 }
 
+// DisposeEntity removes entity (accepted as *QueryResult, QueryResult or
+// *Entity) and all of its components from the Manager. Deferred to the
+// pending buffer instead of applied immediately when called during a
+// Query or QueryEach; see AddComponent and CommitPending. Any
+// OnRemove/Subscribe hooks the dispose triggers run after manager.lock has
+// been released.
 func (manager *Manager) DisposeEntity(entity interface{}) {
 
 	var typedentity *Entity
@@ -356,14 +808,48 @@ func (manager *Manager) DisposeEntity(entity interface{}) {
 		return
 	}
 
+	if manager.deferOrEnqueue(structuralChange{
+		kind:   changeDisposeEntity,
+		entity: typedentity,
+	}) {
+		return
+	}
+
 	manager.lock.Lock()
-	for _, component := range manager.components {
-		if typedentity.HasComponent(component) {
-			typedentity.RemoveComponent(component)
+	hooks := manager.disposeEntity(typedentity)
+	manager.lock.Unlock()
+
+	runHooks(hooks)
+}
+
+// disposeEntity is the lock-free core of DisposeEntity, reused by
+// CommitPending. It returns the OnRemove/Subscribe hooks the dispose
+// triggered, for the caller to run once it has released manager.lock.
+// Callers must hold manager.lock.
+func (manager *Manager) disposeEntity(typedentity *Entity) []func() {
+	var hooks []func()
+
+	if arch, ok := manager.archetypeOf[typedentity.ID]; ok {
+		row := arch.rowOfID[typedentity.ID]
+		for i, component := range arch.components {
+			data := arch.data[i][row]
+			if component.destructor != nil {
+				component.destructor(typedentity, data)
+			}
+			hooks = append(hooks, componentHooks(component, false, typedentity, data)...)
 		}
+
+		tagbefore := typedentity.tag
+		arch.removeEntity(typedentity)
+		delete(manager.archetypeOf, typedentity.ID)
+		typedentity.tag = Tag{}
+
+		hooks = append(hooks, manager.tagChangeHooks(typedentity, tagbefore)...)
 	}
+
 	delete(manager.entitiesByID, typedentity.ID)
-	manager.lock.Unlock()
+
+	return hooks
 }
 
 type QueryResult struct {
@@ -377,53 +863,85 @@ func (manager *Manager) fetchComponentsForEntity(entity *Entity, tag Tag) map[*C
 		return nil
 	}
 
-	componentMap := make(map[*Component]interface{})
-
-	for _, component := range manager.components {
-		if tag.matches(component.tag) {
-			data, ok := entity.GetComponentData(component)
-			if !ok {
-				return nil // if one of the required components is not set, return nothing !
-			}
-
-			componentMap[component] = data
-		}
+	arch := manager.archetypeOf[entity.ID]
+	if arch == nil {
+		return nil
+	}
 
-		// fmt.Printf("-------------\n")
-		// fmt.Printf("%16b : %s\n", int64(tag), "tag")
-		// fmt.Printf("%16b : %s\n", int64(component.tag), "component.tag")
-		// fmt.Printf("%16b : %s\n", int64(entity.tag), "entity.tag")
-		// fmt.Printf("//////////////////\n")
+	row := arch.rowOfID[entity.ID]
+	componentMap := make(map[*Component]interface{})
+	for _, col := range arch.requiredColumns(tag) {
+		componentMap[arch.components[col]] = arch.data[col][row]
 	}
 
 	return componentMap
 }
 
+// Query walks every archetype matching tag and returns a QueryResultCollection,
+// preserved as a compatibility layer over the archetype storage. For hot
+// paths, prefer QueryEach, which doesn't allocate a map per result.
 func (manager *Manager) Query(tag Tag) QueryResultCollection {
-
-	matches := make(QueryResultCollection, 0)
+	atomic.AddInt32(&manager.queryDepth, 1)
+	defer atomic.AddInt32(&manager.queryDepth, -1)
 
 	manager.lock.RLock()
-	for _, entity := range manager.entities {
-		if entity.tag.matches(tag) {
+	defer manager.lock.RUnlock()
 
-			componentMap := make(map[*Component]interface{})
+	return manager.fetchView(tag)
+}
 
-			for _, component := range manager.components {
-				if tag.matches(component.tag) {
-					data, _ := entity.GetComponentData(component)
-					componentMap[component] = data
-				}
+// fetchView is Query without the manager.lock acquisition, for callers that
+// already hold it (e.g. CreateView, seeding a view's entities while it
+// registers the view's subscriptions under the same lock).
+func (manager *Manager) fetchView(tag Tag) QueryResultCollection {
+
+	matches := make(QueryResultCollection, 0)
+
+	for _, arch := range manager.archetypes {
+		if !arch.tag.matches(tag) {
+			continue
+		}
+
+		cols := arch.requiredColumns(tag)
+		for row, entity := range arch.entities {
+			componentMap := make(map[*Component]interface{}, len(cols))
+			for _, col := range cols {
+				componentMap[arch.components[col]] = arch.data[col][row]
 			}
 
 			matches = append(matches, &QueryResult{
 				Entity:     entity,
 				Components: componentMap,
 			})
-
 		}
 	}
-	manager.lock.RUnlock()
 
 	return matches
 }
+
+// QueryEach walks every archetype matching tag, invoking fn with each
+// matching entity and its component data (in ascending ComponentID order),
+// without allocating a map per result.
+func (manager *Manager) QueryEach(tag Tag, fn func(*Entity, ...interface{})) {
+
+	atomic.AddInt32(&manager.queryDepth, 1)
+	defer atomic.AddInt32(&manager.queryDepth, -1)
+
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	for _, arch := range manager.archetypes {
+		if !arch.tag.matches(tag) {
+			continue
+		}
+
+		cols := arch.requiredColumns(tag)
+		args := make([]interface{}, len(cols))
+		for row, entity := range arch.entities {
+			for i, col := range cols {
+				args[i] = arch.data[col][row]
+			}
+			fn(entity, args...)
+		}
+	}
+}
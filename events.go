@@ -0,0 +1,68 @@
+package ecs
+
+// EventKind distinguishes the two transitions a tag subscription can fire
+// on: an entity starting to match, or ceasing to match.
+type EventKind int
+
+const (
+	// EventEnter fires when an entity starts matching a subscribed tag.
+	EventEnter EventKind = iota
+	// EventExit fires when an entity stops matching a subscribed tag.
+	EventExit
+)
+
+// subscription is one Manager.Subscribe registration.
+type subscription struct {
+	tag     Tag
+	kind    EventKind
+	handler func(entity *Entity)
+}
+
+// Subscribe registers handler to be called whenever an entity's tag
+// transition matches kind against tag: EventEnter the moment the entity
+// starts matching tag, EventExit the moment it stops. This lets user code
+// react to e.g. "entity became a walker+talker" without polling Query every
+// frame. Handlers fire as part of AddComponent/RemoveComponent/
+// DisposeEntity/Restore, but only after that call has released the
+// Manager's internal lock, so a handler is free to call back into Query,
+// QueryEach, GetComponentData, AddComponent, RemoveComponent or
+// DisposeEntity without deadlocking.
+func (manager *Manager) Subscribe(tag Tag, kind EventKind, handler func(entity *Entity)) {
+	manager.lock.Lock()
+	manager.subscriptions = append(manager.subscriptions, &subscription{
+		tag:     tag,
+		kind:    kind,
+		handler: handler,
+	})
+	manager.lock.Unlock()
+}
+
+// tagChangeHooks returns a hook call for every subscription whose EventKind
+// matches entity's transition between tagbefore and its current tag, for
+// the caller to run once it has released manager.lock. Callers must hold
+// manager.lock while calling tagChangeHooks itself.
+func (manager *Manager) tagChangeHooks(entity *Entity, tagbefore Tag) []func() {
+	var hooks []func()
+
+	for _, sub := range manager.subscriptions {
+		before := tagbefore.matches(sub.tag)
+		after := entity.tag.matches(sub.tag)
+
+		switch {
+		case sub.kind == EventEnter && !before && after, sub.kind == EventExit && before && !after:
+			sub := sub
+			hooks = append(hooks, func() { sub.handler(entity) })
+		}
+	}
+
+	return hooks
+}
+
+// runHooks calls every hook in order. It's a no-op for a nil/empty slice,
+// so call sites can pass the result of componentHooks/tagChangeHooks
+// straight through without a length check.
+func runHooks(hooks []func()) {
+	for _, hook := range hooks {
+		hook()
+	}
+}
@@ -0,0 +1,117 @@
+package ecs
+
+import "testing"
+
+func TestComponentOnAdd(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	var added int
+	walk.OnAdd(func(e *Entity, data interface{}) { added++ })
+
+	m.NewEntity().AddComponent(walk, "w1")
+	if added != 1 {
+		t.Fatalf("expected onAdd=1, got %d", added)
+	}
+}
+
+func TestComponentOnRemove(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	var removed int
+	walk.OnRemove(func(e *Entity, data interface{}) { removed++ })
+
+	e := m.NewEntity().AddComponent(walk, "w1")
+	e.RemoveComponent(walk)
+	if removed != 1 {
+		t.Fatalf("expected onRemove=1, got %d", removed)
+	}
+}
+
+func TestComponentOnRemoveFiresOnDispose(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	var removed int
+	walk.OnRemove(func(e *Entity, data interface{}) { removed++ })
+
+	e := m.NewEntity().AddComponent(walk, "w1")
+	m.DisposeEntity(e)
+	if removed != 1 {
+		t.Fatalf("expected onRemove=1 on dispose, got %d", removed)
+	}
+}
+
+func TestSubscribeEventEnter(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	var entered int
+	m.Subscribe(BuildTag(walk, talk), EventEnter, func(e *Entity) { entered++ })
+
+	e1 := m.NewEntity().AddComponent(walk, "w1")
+	if entered != 0 {
+		t.Fatalf("expected 0 enter events before entity matches both tags, got %d", entered)
+	}
+
+	e1.AddComponent(talk, "t1")
+	if entered != 1 {
+		t.Fatalf("expected 1 enter event, got %d", entered)
+	}
+}
+
+func TestSubscribeEventExit(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	var exited int
+	m.Subscribe(BuildTag(walk, talk), EventExit, func(e *Entity) { exited++ })
+
+	e1 := m.NewEntity().AddComponent(walk, "w1").AddComponent(talk, "t1")
+
+	e1.RemoveComponent(talk)
+	if exited != 1 {
+		t.Fatalf("expected 1 exit event, got %d", exited)
+	}
+}
+
+func TestSubscribeEventExitOnDispose(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	var exited int
+	m.Subscribe(BuildTag(walk, talk), EventExit, func(e *Entity) { exited++ })
+
+	m.NewEntity().AddComponent(walk, "w1").AddComponent(talk, "t1")
+	m.DisposeEntities(m.Query(BuildTag(walk, talk)).Entities()...)
+	if exited != 1 {
+		t.Fatalf("expected exit fired on dispose, got %d", exited)
+	}
+}
+
+func TestCreateViewStaysInSync(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	view := m.CreateView(BuildTag(walk))
+
+	e1 := m.NewEntity().AddComponent(walk, "w1")
+	if len(view.Get()) != 1 {
+		t.Fatalf("expected view with 1 entity after add, got %d", len(view.Get()))
+	}
+
+	e1.AddComponent(talk, "t1")
+	if len(view.Get()) != 1 {
+		t.Fatalf("expected view to still have 1 entity after unrelated add, got %d", len(view.Get()))
+	}
+
+	e1.RemoveComponent(walk)
+	if len(view.Get()) != 0 {
+		t.Fatalf("expected view empty after removing the matching component, got %d", len(view.Get()))
+	}
+}
@@ -0,0 +1,141 @@
+package ecs
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// System is a unit of per-tick logic run by a Scheduler against a Manager's
+// entities.
+type System interface {
+	Run(dt float64, world *Manager)
+}
+
+// registeredSystem pairs a System with the component tags it declares itself
+// to read and write, used by the Scheduler to detect conflicts.
+type registeredSystem struct {
+	system System
+	reads  Tag
+	writes Tag
+}
+
+// conflicts reports whether a and b must not run concurrently: true if
+// either one's writes overlap the other's reads or writes.
+func (a *registeredSystem) conflicts(b *registeredSystem) bool {
+	return !a.writes.And(b.reads).isEmpty() ||
+		!a.writes.And(b.writes).isEmpty() ||
+		!b.writes.And(a.reads).isEmpty()
+}
+
+// Scheduler runs Systems against a Manager in ordered phases (e.g. input,
+// simulation, render). Phases run one after another; within a phase,
+// systems whose declared read/write tags don't conflict are dispatched
+// concurrently on a worker pool.
+type Scheduler struct {
+	manager *Manager
+
+	lock   sync.Mutex
+	phases map[int][]*registeredSystem
+}
+
+// NewScheduler creates a Scheduler that ticks systems against manager.
+func NewScheduler(manager *Manager) *Scheduler {
+	return &Scheduler{
+		manager: manager,
+		phases:  make(map[int][]*registeredSystem),
+	}
+}
+
+// Register adds system to the given phase, declaring the components it
+// reads and writes via tags (typically built with BuildTag). Phases run in
+// ascending order; within a phase, Tick dispatches conflict-free systems
+// concurrently.
+func (scheduler *Scheduler) Register(phase int, system System, reads Tag, writes Tag) *Scheduler {
+	scheduler.lock.Lock()
+	scheduler.phases[phase] = append(scheduler.phases[phase], &registeredSystem{
+		system: system,
+		reads:  reads,
+		writes: writes,
+	})
+	scheduler.lock.Unlock()
+
+	return scheduler
+}
+
+// Tick runs every registered system once, phase by phase in ascending
+// order. Within a phase, systems are grouped into waves so that any two
+// systems in the same wave have disjoint write sets from each other's
+// read+write sets, and each wave is dispatched concurrently on a worker
+// pool via errgroup. Once every phase has run, it calls CommitPending to
+// apply any structural change a system deferred by calling AddComponent,
+// RemoveComponent or DisposeEntity from inside a Query or QueryEach.
+func (scheduler *Scheduler) Tick(dt float64) error {
+	scheduler.lock.Lock()
+	phaseNumbers := make([]int, 0, len(scheduler.phases))
+	for phase := range scheduler.phases {
+		phaseNumbers = append(phaseNumbers, phase)
+	}
+	sort.Ints(phaseNumbers)
+
+	waves := make([][][]*registeredSystem, len(phaseNumbers))
+	for i, phase := range phaseNumbers {
+		waves[i] = buildWaves(scheduler.phases[phase])
+	}
+	scheduler.lock.Unlock()
+
+	for _, phaseWaves := range waves {
+		for _, wave := range phaseWaves {
+			if err := runWave(dt, scheduler.manager, wave); err != nil {
+				return err
+			}
+		}
+	}
+
+	scheduler.manager.CommitPending()
+
+	return nil
+}
+
+// buildWaves groups systems into ordered waves: within a wave, no two
+// systems conflict, so they're safe to run concurrently; a system is
+// placed in the first wave after every system it conflicts with.
+func buildWaves(systems []*registeredSystem) [][]*registeredSystem {
+	waveOf := make([]int, len(systems))
+
+	maxWave := 0
+	for i, system := range systems {
+		wave := 0
+		for j := 0; j < i; j++ {
+			if system.conflicts(systems[j]) && waveOf[j] >= wave {
+				wave = waveOf[j] + 1
+			}
+		}
+		waveOf[i] = wave
+		if wave > maxWave {
+			maxWave = wave
+		}
+	}
+
+	waves := make([][]*registeredSystem, maxWave+1)
+	for i, system := range systems {
+		waves[waveOf[i]] = append(waves[waveOf[i]], system)
+	}
+
+	return waves
+}
+
+func runWave(dt float64, world *Manager, wave []*registeredSystem) error {
+	g := new(errgroup.Group)
+
+	for _, rs := range wave {
+		rs := rs
+		g.Go(func() error {
+			rs.system.Run(dt, world)
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,77 @@
+package ecs
+
+import (
+	"sync"
+	"testing"
+)
+
+type noopSystem struct{}
+
+func (noopSystem) Run(dt float64, world *Manager) {}
+
+func TestBuildWavesGroupsConflictFreeSystems(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+
+	readWalk := &registeredSystem{system: noopSystem{}, reads: BuildTag(walk)}
+	writeWalk := &registeredSystem{system: noopSystem{}, writes: BuildTag(walk)}
+	writeTalk := &registeredSystem{system: noopSystem{}, writes: BuildTag(talk)}
+
+	waves := buildWaves([]*registeredSystem{readWalk, writeWalk, writeTalk})
+
+	if len(waves) != 2 {
+		t.Fatalf("expected readWalk and writeWalk to conflict into 2 waves, got %d", len(waves))
+	}
+	if len(waves[0]) != 2 {
+		t.Fatalf("expected readWalk and writeTalk (no shared components) to share wave 0, got %d systems", len(waves[0]))
+	}
+	if len(waves[1]) != 1 || waves[1][0] != writeWalk {
+		t.Fatalf("expected writeWalk to be pushed to its own later wave")
+	}
+}
+
+func TestBuildWavesAllConflictFreeRunInOneWave(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+	fly := m.NewComponent()
+
+	systems := []*registeredSystem{
+		{system: noopSystem{}, reads: BuildTag(walk)},
+		{system: noopSystem{}, reads: BuildTag(talk)},
+		{system: noopSystem{}, reads: BuildTag(fly)},
+	}
+
+	waves := buildWaves(systems)
+	if len(waves) != 1 || len(waves[0]) != 3 {
+		t.Fatalf("expected all 3 read-only systems in a single wave, got %d waves", len(waves))
+	}
+}
+
+func TestSchedulerTickRunsPhasesInOrder(t *testing.T) {
+	m := NewManager()
+	scheduler := NewScheduler(m)
+
+	var log []string
+	var lock sync.Mutex
+
+	record := func(name string) System {
+		return systemFunc(func(dt float64, world *Manager) {
+			lock.Lock()
+			log = append(log, name)
+			lock.Unlock()
+		})
+	}
+
+	scheduler.Register(1, record("late"), Tag{}, Tag{})
+	scheduler.Register(0, record("early"), Tag{}, Tag{})
+
+	if err := scheduler.Tick(0.1); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+
+	if len(log) != 2 || log[0] != "early" || log[1] != "late" {
+		t.Fatalf("expected phase 0 to run before phase 1, got %v", log)
+	}
+}
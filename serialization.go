@@ -0,0 +1,200 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec marshals and unmarshals both a whole Manager snapshot and each
+// individual component's data. Manager defaults to JSONCodec; swap in a
+// different Codec (gob, msgpack...) with SetCodec before calling Snapshot
+// or Restore.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the default Codec, backed by encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// SetCodec changes the Codec used by Snapshot and Restore. It must be
+// called before either is used; it is not safe for concurrent use with
+// Snapshot/Restore.
+func (manager *Manager) SetCodec(codec Codec) {
+	manager.codec = codec
+}
+
+// RegisterComponentType tells the Manager how to allocate fresh component
+// data of the concrete type backing component, so that Restore can
+// unmarshal into it. It must be called for every component that a
+// Snapshot may contain, before calling Restore.
+func (manager *Manager) RegisterComponentType(component *Component, factory func() interface{}) {
+	manager.lock.Lock()
+	manager.componentFactories[component.id] = factory
+	manager.lock.Unlock()
+}
+
+type componentSnapshot struct {
+	ComponentID ComponentID `json:"component_id"`
+	Data        []byte      `json:"data"`
+}
+
+type entitySnapshot struct {
+	ID         EntityID            `json:"id"`
+	Components []componentSnapshot `json:"components"`
+}
+
+type managerSnapshot struct {
+	Entities []entitySnapshot `json:"entities"`
+}
+
+// Snapshot serializes every entity, its components and their data using the
+// Manager's Codec (JSON by default). The result can later be handed to
+// Restore, on this Manager or a fresh one that has registered the same
+// component types via RegisterComponentType.
+func (manager *Manager) Snapshot() ([]byte, error) {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	snapshot := managerSnapshot{}
+
+	for _, arch := range manager.archetypes {
+		for row, entity := range arch.entities {
+			es := entitySnapshot{
+				ID:         entity.ID,
+				Components: make([]componentSnapshot, 0, len(arch.components)),
+			}
+
+			for col, component := range arch.components {
+				raw, err := manager.codec.Marshal(arch.data[col][row])
+				if err != nil {
+					return nil, fmt.Errorf("ecs: snapshot: marshal component %d of entity %s: %w", component.id, entity.ID, err)
+				}
+
+				es.Components = append(es.Components, componentSnapshot{
+					ComponentID: component.id,
+					Data:        raw,
+				})
+			}
+
+			snapshot.Entities = append(snapshot.Entities, es)
+		}
+	}
+
+	return manager.codec.Marshal(snapshot)
+}
+
+// Restore replaces the Manager's entities with the ones serialized in data
+// by a prior Snapshot. Every component referenced in data must have been
+// registered beforehand via RegisterComponentType, so Restore knows which
+// concrete type to allocate before unmarshaling into it. The restored world
+// is built up entirely off to the side; if any component is unknown, lacks
+// a registered factory, or fails to unmarshal, Restore returns the error
+// without having touched the live Manager at all, and without having fired
+// a single OnAdd/OnRemove/Subscribe hook. Only once every entity and
+// component in data has been successfully rebuilt are the Manager's fields
+// swapped over and those hooks fired, as if every old entity had been
+// disposed and every restored one freshly built, so observers never see a
+// partially-restored world.
+func (manager *Manager) Restore(data []byte) error {
+	var snapshot managerSnapshot
+	if err := manager.codec.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("ecs: restore: %w", err)
+	}
+
+	manager.lock.Lock()
+
+	componentsByID := make(map[ComponentID]*Component, len(manager.components))
+	for _, component := range manager.components {
+		componentsByID[component.id] = component
+	}
+
+	// Rebuild the new world in a scratch Manager, sharing only the
+	// read-only component/codec/factory configuration. Nothing here
+	// touches the live Manager's fields, so a mid-loop error below leaves
+	// it completely untouched. scratch has no subscriptions of its own, so
+	// addComponent's returned hooks are only the components' OnAdd
+	// handlers; they're collected rather than run immediately, so a later
+	// error in the loop still discards them along with everything else.
+	scratch := &Manager{
+		entities:           make([]*Entity, 0, len(snapshot.Entities)),
+		entitiesByID:       make(map[EntityID]*Entity, len(snapshot.Entities)),
+		archetypes:         make(map[string]*archetype),
+		archetypeOf:        make(map[EntityID]*archetype, len(snapshot.Entities)),
+		componentFactories: manager.componentFactories,
+	}
+
+	var onAddHooks []func()
+
+	for _, es := range snapshot.Entities {
+		entity := &Entity{ID: es.ID, manager: manager}
+		scratch.entities = append(scratch.entities, entity)
+		scratch.entitiesByID[entity.ID] = entity
+		scratch.archetypeOf[entity.ID] = scratch.getArchetype(nil)
+		scratch.archetypeOf[entity.ID].addEntity(entity, nil)
+
+		for _, cs := range es.Components {
+			component, ok := componentsByID[cs.ComponentID]
+			if !ok {
+				manager.lock.Unlock()
+				return fmt.Errorf("ecs: restore: unknown component id %d on entity %s", cs.ComponentID, entity.ID)
+			}
+
+			factory, ok := manager.componentFactories[component.id]
+			if !ok {
+				manager.lock.Unlock()
+				return fmt.Errorf("ecs: restore: component %d has no factory; call RegisterComponentType before Restore", component.id)
+			}
+
+			instance := factory()
+			if err := manager.codec.Unmarshal(cs.Data, instance); err != nil {
+				manager.lock.Unlock()
+				return fmt.Errorf("ecs: restore: unmarshal component %d of entity %s: %w", component.id, entity.ID, err)
+			}
+
+			onAddHooks = append(onAddHooks, scratch.addComponent(entity, component, instance)...)
+		}
+	}
+
+	// Every entity restored successfully: swap the rebuilt state in and
+	// collect the subscriber notifications for the transition.
+	oldEntities := manager.entities
+	oldArchetypeOf := manager.archetypeOf
+
+	manager.entities = scratch.entities
+	manager.entitiesByID = scratch.entitiesByID
+	manager.archetypes = scratch.archetypes
+	manager.archetypeOf = scratch.archetypeOf
+
+	hooks := onAddHooks
+	for _, oldEntity := range oldEntities {
+		if arch, ok := oldArchetypeOf[oldEntity.ID]; ok {
+			row := arch.rowOfID[oldEntity.ID]
+			for i, component := range arch.components {
+				hooks = append(hooks, componentHooks(component, false, oldEntity, arch.data[i][row])...)
+			}
+		}
+
+		tagbefore := oldEntity.tag
+		oldEntity.tag = Tag{}
+		hooks = append(hooks, manager.tagChangeHooks(oldEntity, tagbefore)...)
+	}
+
+	for _, entity := range manager.entities {
+		hooks = append(hooks, manager.tagChangeHooks(entity, Tag{})...)
+	}
+
+	manager.lock.Unlock()
+
+	runHooks(hooks)
+
+	return nil
+}
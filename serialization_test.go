@@ -0,0 +1,129 @@
+package ecs
+
+import "testing"
+
+// restoredString unwraps the *string a RegisterComponentType factory of
+// func() interface{} { return new(string) } produces once Restore has
+// unmarshaled into it.
+func restoredString(v interface{}) string {
+	return *(v.(*string))
+}
+
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+	m.RegisterComponentType(walk, func() interface{} { return new(string) })
+	m.RegisterComponentType(talk, func() interface{} { return new(string) })
+
+	m.NewEntity().AddComponent(walk, "w1")
+	m.NewEntity().AddComponent(walk, "w2").AddComponent(talk, "t2")
+
+	data, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	// A fresh Manager that creates its components in the same order gets the
+	// same ComponentIDs, which is what Restore matches snapshot data against.
+	m2 := NewManager()
+	walk2 := m2.NewComponent()
+	talk2 := m2.NewComponent()
+	m2.RegisterComponentType(walk2, func() interface{} { return new(string) })
+	m2.RegisterComponentType(talk2, func() interface{} { return new(string) })
+
+	if err := m2.Restore(data); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	results := m2.Query(BuildTag(walk2))
+	if len(results) != 2 {
+		t.Fatalf("expected 2 entities carrying walk after restore, got %d", len(results))
+	}
+
+	for _, res := range results {
+		walkData := restoredString(res.Components[walk2])
+		if walkData != "w1" && walkData != "w2" {
+			t.Fatalf("unexpected walk data after restore: %v", walkData)
+		}
+	}
+
+	talkers := m2.Query(BuildTag(walk2, talk2))
+	if len(talkers) != 1 {
+		t.Fatalf("expected 1 restored entity to still carry both walk and talk, got %d", len(talkers))
+	}
+	if talkData := restoredString(talkers[0].Components[talk2]); talkData != "t2" {
+		t.Fatalf("expected restored talk data to be t2, got %v", talkData)
+	}
+}
+
+func TestRestoreReplacesExistingEntities(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	m.RegisterComponentType(walk, func() interface{} { return new(string) })
+
+	m.NewEntity().AddComponent(walk, "w1")
+	data, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	m.NewEntity().AddComponent(walk, "stale")
+	m.NewEntity().AddComponent(walk, "also stale")
+
+	if err := m.Restore(data); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	results := m.Query(BuildTag(walk))
+	if len(results) != 1 || restoredString(results[0].Components[walk]) != "w1" {
+		t.Fatalf("expected Restore to replace the live entities with the snapshot's, got %d results", len(results))
+	}
+}
+
+func TestRestoreFailsOnUnregisteredComponent(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	m.RegisterComponentType(walk, func() interface{} { return new(string) })
+
+	m.NewEntity().AddComponent(walk, "w1")
+	data, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	m2 := NewManager()
+	// m2 never calls RegisterComponentType for walk's id.
+	if err := m2.Restore(data); err == nil {
+		t.Fatalf("expected restore to fail without a registered factory")
+	}
+}
+
+func TestRestoreLeavesManagerUntouchedOnError(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	ghost := m.NewComponent() // never registered
+	m.RegisterComponentType(walk, func() interface{} { return new(string) })
+
+	m.NewEntity().AddComponent(walk, "w1")
+	m.NewEntity().AddComponent(ghost, "g1")
+
+	data, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	m2 := NewManager()
+	walk2 := m2.NewComponent()
+	m2.RegisterComponentType(walk2, func() interface{} { return new(string) })
+	m2.NewEntity().AddComponent(walk2, "pre-existing")
+
+	if err := m2.Restore(data); err == nil {
+		t.Fatalf("expected restore to fail on the entity carrying ghost")
+	}
+
+	results := m2.Query(BuildTag(walk2))
+	if len(results) != 1 || results[0].Components[walk2] != "pre-existing" {
+		t.Fatalf("expected m2 to be untouched by the failed restore, got %v", results)
+	}
+}
@@ -0,0 +1,121 @@
+package ecs
+
+import "testing"
+
+func TestTagAndOrAndNot(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+	fly := m.NewComponent()
+
+	walkTalk := BuildTag(walk, talk)
+	walkFly := BuildTag(walk, fly)
+
+	if got := walkTalk.And(walkFly); !got.matches(BuildTag(walk)) || got.matches(BuildTag(talk)) {
+		t.Fatalf("expected And to keep only the shared component")
+	}
+
+	if got := walkTalk.Or(walkFly); !got.matches(BuildTag(walk, talk, fly)) {
+		t.Fatalf("expected Or to require every component from both tags")
+	}
+
+	if got := walkTalk.AndNot(BuildTag(talk)); !got.matches(BuildTag(walk)) || got.matches(BuildTag(talk)) {
+		t.Fatalf("expected AndNot to clear talk's bit and keep walk's")
+	}
+}
+
+func TestTagAllRequiresEveryGroup(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+	fly := m.NewComponent()
+
+	tag := BuildTag(walk).All(BuildTag(talk), BuildTag(fly))
+
+	e := m.NewEntity().AddComponent(walk, "w").AddComponent(talk, "t")
+	if e.Matches(tag) {
+		t.Fatalf("expected All to require fly too")
+	}
+
+	e.AddComponent(fly, "f")
+	if !e.Matches(tag) {
+		t.Fatalf("expected All to match once every required component is present")
+	}
+}
+
+func TestTagAnyMatchesOneOfAlternatives(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+	talk := m.NewComponent()
+	fly := m.NewComponent()
+
+	tag := BuildTag(walk).Any(BuildTag(talk), BuildTag(fly))
+
+	e := m.NewEntity().AddComponent(walk, "w")
+	if e.Matches(tag) {
+		t.Fatalf("expected Any to require at least one alternative")
+	}
+
+	e.AddComponent(talk, "t")
+	if !e.Matches(tag) {
+		t.Fatalf("expected Any to match once one alternative is satisfied")
+	}
+}
+
+func TestTagInverseNegatesMatch(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	notWalk := BuildTag(walk).Inverse()
+
+	e := m.NewEntity()
+	if !e.Matches(notWalk) {
+		t.Fatalf("expected Inverse tag to match an entity lacking the component")
+	}
+
+	e.AddComponent(walk, "w")
+	if e.Matches(notWalk) {
+		t.Fatalf("expected Inverse tag to stop matching once the component is present")
+	}
+}
+
+// TestTagSpillsPastFirstWord ensures the bitset grows into extra once more
+// than 64 components exist, and that matching still works across the word
+// boundary.
+func TestTagSpillsPastFirstWord(t *testing.T) {
+	m := NewManager()
+
+	var components []*Component
+	for i := 0; i < 70; i++ {
+		components = append(components, m.NewComponent())
+	}
+	last := components[69]
+
+	tag := BuildTag(last)
+	if tag.numWords() < 2 {
+		t.Fatalf("expected the 70th component's bit to spill into a second word, got %d words", tag.numWords())
+	}
+
+	e := m.NewEntity()
+	if e.Matches(tag) {
+		t.Fatalf("expected no match before adding the component")
+	}
+
+	e.AddComponent(last, "last")
+	if !e.Matches(tag) {
+		t.Fatalf("expected the entity to match once it carries the 70th component")
+	}
+}
+
+func TestTagIsEmpty(t *testing.T) {
+	m := NewManager()
+	walk := m.NewComponent()
+
+	if !(Tag{}).isEmpty() {
+		t.Fatalf("expected the zero Tag to be empty")
+	}
+
+	if BuildTag(walk).isEmpty() {
+		t.Fatalf("expected a tag built from a component to not be empty")
+	}
+}